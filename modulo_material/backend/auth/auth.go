@@ -0,0 +1,217 @@
+// Package auth provides API-key based authentication and per-subject ACLs
+// for the material backend.
+package auth
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Key represents a row in the auth_key table.
+type Key struct {
+	Key             string `json:"key"`
+	Owner           string `json:"owner"`
+	AllowedSubjects string `json:"allowed_subjects"` // CSV, empty means all subjects
+	CreatedAt       string `json:"created_at"`
+	ExpiresAt       string `json:"expires_at"` // empty means no expiry
+}
+
+// EnsureTable creates the auth_key table if it doesn't already exist.
+func EnsureTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS auth_key (
+		key TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		allowed_subjects TEXT NOT NULL DEFAULT '',
+		created_at TEXT NOT NULL,
+		expires_at TEXT NOT NULL DEFAULT ''
+	);`)
+	return err
+}
+
+// generateKey returns a random 32-byte hex token.
+func generateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateKey mints a new key scoped to allowedSubjects (empty = all subjects)
+// and persists it.
+func CreateKey(db *sql.DB, owner string, allowedSubjects []string, ttl time.Duration) (Key, error) {
+	token, err := generateKey()
+	if err != nil {
+		return Key{}, err
+	}
+	k := Key{
+		Key:             token,
+		Owner:           owner,
+		AllowedSubjects: strings.Join(allowedSubjects, ","),
+		CreatedAt:       time.Now().Format(time.RFC3339),
+	}
+	if ttl > 0 {
+		k.ExpiresAt = time.Now().Add(ttl).Format(time.RFC3339)
+	}
+	_, err = db.Exec(`INSERT INTO auth_key (key, owner, allowed_subjects, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		k.Key, k.Owner, k.AllowedSubjects, k.CreatedAt, k.ExpiresAt)
+	if err != nil {
+		return Key{}, err
+	}
+	return k, nil
+}
+
+// lookup fetches a key row by its token, or ok=false if it doesn't exist.
+func lookup(db *sql.DB, token string) (Key, bool) {
+	var k Key
+	row := db.QueryRow(`SELECT key, owner, allowed_subjects, created_at, expires_at FROM auth_key WHERE key = ?`, token)
+	if err := row.Scan(&k.Key, &k.Owner, &k.AllowedSubjects, &k.CreatedAt, &k.ExpiresAt); err != nil {
+		return Key{}, false
+	}
+	return k, true
+}
+
+func (k Key) expired() bool {
+	if k.ExpiresAt == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, k.ExpiresAt)
+	return err == nil && time.Now().After(t)
+}
+
+func (k Key) allows(subjectID string) bool {
+	if k.AllowedSubjects == "" {
+		return true
+	}
+	for _, s := range strings.Split(k.AllowedSubjects, ",") {
+		if strings.TrimSpace(s) == subjectID {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenFromRequest extracts the bearer token from the Authorization header
+// or, failing that, from the "auth" cookie.
+func tokenFromRequest(c *gin.Context) string {
+	if h := c.GetHeader("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimPrefix(h, "Bearer ")
+	}
+	if cookie, err := c.Cookie("auth"); err == nil {
+		return cookie
+	}
+	return ""
+}
+
+// AuthenticateToken validates a bearer token the same way the Gin middleware
+// below does, for callers that don't have a *gin.Context to pull it from
+// (e.g. the gRPC server, which reads it out of incoming call metadata).
+func AuthenticateToken(db *sql.DB, token string) (Key, bool) {
+	if token == "" {
+		return Key{}, false
+	}
+	k, ok := lookup(db, token)
+	if !ok || k.expired() {
+		return Key{}, false
+	}
+	return k, true
+}
+
+// Allows reports whether k is scoped to allow subjectID. Exported so
+// non-gin callers (e.g. the gRPC interceptors) can reuse the same ACL logic
+// as AllowsSubject below.
+func (k Key) Allows(subjectID string) bool {
+	return k.allows(subjectID)
+}
+
+// authenticate validates the caller's API key and, on success, stores both
+// the owner and the resolved Key on the context for downstream handlers.
+func authenticate(db *sql.DB, c *gin.Context) (Key, bool) {
+	token := tokenFromRequest(c)
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing auth token"})
+		return Key{}, false
+	}
+
+	k, ok := AuthenticateToken(db, token)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired auth token"})
+		return Key{}, false
+	}
+
+	c.Set("auth_owner", k.Owner)
+	c.Set("auth_key", k)
+	return k, true
+}
+
+// RequireSubjectAccess returns a Gin middleware that validates the caller's
+// API key and ensures it's allowed to act on the subject_id found in the
+// request (form value, query param or URL path param, in that order; a JSON
+// body is not read). Only suitable for routes where subject_id is actually
+// present in one of those places; routes that resolve subject_id from other state (e.g. a loaded
+// row) should use RequireValidKey and call AllowsSubject explicitly.
+func RequireSubjectAccess(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k, ok := authenticate(db, c)
+		if !ok {
+			return
+		}
+
+		subjectID := c.PostForm("subject_id")
+		if subjectID == "" {
+			subjectID = c.Query("subject_id")
+		}
+		if subjectID == "" {
+			subjectID = c.Param("subject_id")
+		}
+
+		// subject_id is required, not merely checked when present: an absent
+		// subject_id isn't "any subject is fine", it's a request that must
+		// be rejected, or a caller could skip the ACL check entirely just by
+		// omitting it.
+		if subjectID == "" || !k.allows(subjectID) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "key not authorized for this subject"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireValidKey returns a Gin middleware that only checks the caller's API
+// key is valid and not expired, deferring the subject-scope check to the
+// handler via AllowsSubject. Use this when subject_id isn't known until
+// after the handler has loaded the resource being acted on.
+func RequireValidKey(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := authenticate(db, c); !ok {
+			return
+		}
+		c.Next()
+	}
+}
+
+// AllowsSubject reports whether the API key validated for this request (by
+// RequireSubjectAccess or RequireValidKey) is scoped to subjectID.
+func AllowsSubject(c *gin.Context, subjectID string) bool {
+	k, ok := c.MustGet("auth_key").(Key)
+	return ok && k.allows(subjectID)
+}
+
+// RequireMasterKey protects admin-only endpoints behind a master key read
+// from an environment variable.
+func RequireMasterKey(masterKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if masterKey == "" || tokenFromRequest(c) != masterKey {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid master key"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := EnsureTable(db); err != nil {
+		t.Fatalf("ensure table: %v", err)
+	}
+	return db
+}
+
+func newTestRouter(db *sql.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/upload", RequireSubjectAccess(db), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+// TestRequireSubjectAccessRequiresSubjectID guards against the absent
+// subject_id case being silently treated as "any subject is allowed": a
+// valid key scoped to one subject must not be able to upload under no
+// subject_id at all.
+func TestRequireSubjectAccessRequiresSubjectID(t *testing.T) {
+	db := openTestDB(t)
+	k, err := CreateKey(db, "owner", []string{"math-101"}, 0)
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+	r := newTestRouter(db)
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(url.Values{}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+k.Key)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d for a request with no subject_id", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestRequireSubjectAccessAllowsScopedSubject(t *testing.T) {
+	db := openTestDB(t)
+	k, err := CreateKey(db, "owner", []string{"math-101"}, 0)
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+	r := newTestRouter(db)
+
+	form := url.Values{"subject_id": {"math-101"}}
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+k.Key)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d for a key scoped to the requested subject", rec.Code, http.StatusOK)
+	}
+}
+
+func TestKeyAllows(t *testing.T) {
+	cases := []struct {
+		name      string
+		allowed   string
+		subjectID string
+		want      bool
+	}{
+		{"unscoped key allows any subject", "", "math-101", true},
+		{"scoped key allows listed subject", "math-101,phys-201", "math-101", true},
+		{"scoped key rejects unlisted subject", "math-101,phys-201", "history-301", false},
+		{"scoped key trims whitespace around entries", "math-101, phys-201", "phys-201", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			k := Key{AllowedSubjects: c.allowed}
+			if got := k.allows(c.subjectID); got != c.want {
+				t.Errorf("allows(%q) with AllowedSubjects=%q = %v, want %v", c.subjectID, c.allowed, got, c.want)
+			}
+		})
+	}
+}
+
+func TestKeyExpired(t *testing.T) {
+	if (Key{}).expired() {
+		t.Error("key with no expires_at should never expire")
+	}
+	if (Key{ExpiresAt: time.Now().Add(time.Hour).Format(time.RFC3339)}).expired() {
+		t.Error("key expiring in the future should not be expired")
+	}
+	if !(Key{ExpiresAt: time.Now().Add(-time.Hour).Format(time.RFC3339)}).expired() {
+		t.Error("key that expired an hour ago should be expired")
+	}
+}
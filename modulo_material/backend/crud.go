@@ -0,0 +1,336 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"domotica/modulo_material/backend/auth"
+)
+
+// ensureSearchFTS creates the FTS5 index over title/description, kept in
+// sync with the material table via triggers, and backfills it for rows
+// that existed before this migration.
+func ensureSearchFTS() error {
+	if _, err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS material_search_fts USING fts5(
+		title, description, content='material', content_rowid='id'
+	);`); err != nil {
+		return err
+	}
+
+	triggers := []string{
+		`CREATE TRIGGER IF NOT EXISTS material_search_ai AFTER INSERT ON material BEGIN
+			INSERT INTO material_search_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS material_search_ad AFTER DELETE ON material BEGIN
+			INSERT INTO material_search_fts(material_search_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+		END;`,
+		`CREATE TRIGGER IF NOT EXISTS material_search_au AFTER UPDATE ON material BEGIN
+			INSERT INTO material_search_fts(material_search_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+			INSERT INTO material_search_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+		END;`,
+	}
+	for _, stmt := range triggers {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Exec(`INSERT INTO material_search_fts(material_search_fts) VALUES ('rebuild')`)
+	return err
+}
+
+func getMaterialByID(id int64) (Material, bool, error) {
+	var m Material
+	var mimeType, description, contentHash sql.NullString
+	row := db.QueryRow(`SELECT id, subject_id, title, logical_type, file_path, original_name, mime_type, description, created_at, content_hash
+		FROM material WHERE id = ?`, id)
+	err := row.Scan(&m.ID, &m.SubjectID, &m.Title, &m.LogicalType, &m.FilePath, &m.OriginalName, &mimeType, &description, &m.CreatedAt, &contentHash)
+	if err == sql.ErrNoRows {
+		return Material{}, false, nil
+	}
+	if err != nil {
+		return Material{}, false, err
+	}
+	if mimeType.Valid {
+		m.MimeType = mimeType.String
+	}
+	if description.Valid {
+		m.Description = description.String
+	}
+	if contentHash.Valid {
+		m.ContentHash = contentHash.String
+	}
+	return m, true, nil
+}
+
+// deleteMaterial removes the DB row and, if this was the last reference to
+// its underlying content, the stored file too.
+func deleteMaterial(id int64) error {
+	m, ok, err := getMaterialByID(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	res, err := db.Exec("DELETE FROM material WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	// Guard against a concurrent or client-retried delete of the same id: if
+	// another call already removed this row between our getMaterialByID and
+	// this DELETE, rows affected is 0 and we must not release the blob a
+	// second time (that would double-decrement ref_count).
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return nil
+	}
+
+	if m.ContentHash == "" {
+		return nil
+	}
+	blobPath, canDelete, err := releaseBlob(m.ContentHash)
+	if err != nil || !canDelete {
+		return err
+	}
+	p, rel, err := storage.resolve(blobPath)
+	if err != nil {
+		return err
+	}
+	return p.Delete(rel)
+}
+
+var allowedSortFields = map[string]bool{
+	"created_at": true,
+	"title":      true,
+	"subject_id": true,
+}
+
+// searchMaterial backs GET /api/material/search, combining an optional FTS5
+// text query with simple equality/range filters and pagination.
+func searchMaterial(q, subjectID, logicalType, mime, since, until, sort string, limit, offset int) ([]Material, int, error) {
+	var conditions []string
+	var args []interface{}
+
+	from := "material m"
+	if q != "" {
+		from = "material m JOIN material_search_fts fts ON fts.rowid = m.id"
+		conditions = append(conditions, "material_search_fts MATCH ?")
+		args = append(args, q)
+	}
+	if subjectID != "" {
+		conditions = append(conditions, "m.subject_id = ?")
+		args = append(args, subjectID)
+	}
+	if logicalType != "" {
+		conditions = append(conditions, "m.logical_type = ?")
+		args = append(args, logicalType)
+	}
+	if mime != "" {
+		conditions = append(conditions, "m.mime_type = ?")
+		args = append(args, mime)
+	}
+	if since != "" {
+		conditions = append(conditions, "m.created_at >= ?")
+		args = append(args, since)
+	}
+	if until != "" {
+		conditions = append(conditions, "m.created_at <= ?")
+		args = append(args, until)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", from, where)
+	if err := db.QueryRow(countSQL, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	sortField, sortDir, ok := strings.Cut(sort, ":")
+	if !ok || !allowedSortFields[sortField] {
+		sortField, sortDir = "created_at", "desc"
+	}
+	if sortDir != "asc" && sortDir != "desc" {
+		sortDir = "desc"
+	}
+
+	listSQL := fmt.Sprintf(`SELECT m.id, m.subject_id, m.title, m.logical_type, m.file_path, m.original_name, m.mime_type, m.description, m.created_at, m.content_hash
+		FROM %s%s ORDER BY m.%s %s LIMIT ? OFFSET ?`, from, where, sortField, sortDir)
+	rows, err := db.Query(listSQL, append(append([]interface{}{}, args...), limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var materials []Material
+	for rows.Next() {
+		var m Material
+		var mimeType, description, contentHash sql.NullString
+		if err := rows.Scan(&m.ID, &m.SubjectID, &m.Title, &m.LogicalType, &m.FilePath, &m.OriginalName, &mimeType, &description, &m.CreatedAt, &contentHash); err != nil {
+			return nil, 0, err
+		}
+		if mimeType.Valid {
+			m.MimeType = mimeType.String
+		}
+		if description.Valid {
+			m.Description = description.String
+		}
+		if contentHash.Valid {
+			m.ContentHash = contentHash.String
+		}
+		materials = append(materials, m)
+	}
+	return materials, total, nil
+}
+
+// registerCRUDRoutes wires GET/PATCH/DELETE by id plus search onto api.
+func registerCRUDRoutes(api *gin.RouterGroup) {
+	api.GET("/material/:id", func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid material id"})
+			return
+		}
+		m, ok, err := getMaterialByID(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "material not found"})
+			return
+		}
+		c.JSON(http.StatusOK, m)
+	})
+
+	api.PATCH("/material/:id", auth.RequireValidKey(db), func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid material id"})
+			return
+		}
+
+		m, ok, err := getMaterialByID(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "material not found"})
+			return
+		}
+		if !auth.AllowsSubject(c, m.SubjectID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "key not authorized for this subject"})
+			return
+		}
+
+		var req struct {
+			Title       *string `json:"title"`
+			Description *string `json:"description"`
+			LogicalType *string `json:"logical_type"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if req.Title != nil {
+			m.Title = strings.TrimSpace(*req.Title)
+		}
+		if req.Description != nil {
+			m.Description = strings.TrimSpace(*req.Description)
+		}
+		if req.LogicalType != nil {
+			m.LogicalType = strings.TrimSpace(*req.LogicalType)
+		}
+
+		if _, err := db.Exec("UPDATE material SET title = ?, description = ?, logical_type = ? WHERE id = ?",
+			m.Title, m.Description, m.LogicalType, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, m)
+	})
+
+	api.DELETE("/material/:id", auth.RequireValidKey(db), func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid material id"})
+			return
+		}
+		m, ok, err := getMaterialByID(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		} else if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "material not found"})
+			return
+		}
+		if !auth.AllowsSubject(c, m.SubjectID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "key not authorized for this subject"})
+			return
+		}
+		if err := deleteMaterial(id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	api.GET("/material/search", func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil || limit <= 0 || limit > 200 {
+			limit = 20
+		}
+		offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if err != nil || offset < 0 {
+			offset = 0
+		}
+
+		materials, total, err := searchMaterial(
+			c.Query("q"), c.Query("subject_id"), c.Query("logical_type"), c.Query("mime"),
+			c.Query("since"), c.Query("until"), c.Query("sort"), limit, offset,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if materials == nil {
+			materials = []Material{}
+		}
+
+		c.Header("X-Total-Count", strconv.Itoa(total))
+
+		base := c.Request.URL
+		query := base.Query()
+		var next, prev string
+		if offset+limit < total {
+			query.Set("offset", strconv.Itoa(offset+limit))
+			next = base.Path + "?" + query.Encode()
+		}
+		if offset > 0 {
+			prevOffset := offset - limit
+			if prevOffset < 0 {
+				prevOffset = 0
+			}
+			query.Set("offset", strconv.Itoa(prevOffset))
+			prev = base.Path + "?" + query.Encode()
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"materials": materials,
+			"next":      next,
+			"prev":      prev,
+		})
+	})
+}
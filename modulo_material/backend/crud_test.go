@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+// TestDeleteMaterialRetryDoesNotDoubleRelease simulates a client-retried
+// DELETE of the same material id, run after the first one has finished: the
+// second call finds no row and must not call releaseBlob again, or it would
+// double-decrement ref_count out from under a sibling material still
+// pointing at the same blob. The two calls run one after the other, so the
+// zero-rows-affected guard for a racing delete is not exercised here.
+func TestDeleteMaterialRetryDoesNotDoubleRelease(t *testing.T) {
+	openTestDB(t)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS material (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		subject_id TEXT NOT NULL,
+		title TEXT NOT NULL,
+		logical_type TEXT NOT NULL,
+		file_path TEXT NOT NULL,
+		original_name TEXT NOT NULL,
+		mime_type TEXT,
+		description TEXT,
+		created_at TEXT NOT NULL,
+		content_hash TEXT
+	);`); err != nil {
+		t.Fatalf("create material table: %v", err)
+	}
+
+	if _, _, err := claimBlob("hash1", "local://a/one.bin"); err != nil {
+		t.Fatalf("claimBlob for material one: %v", err)
+	}
+	if _, _, err := claimBlob("hash1", "local://b/two.bin"); err != nil {
+		t.Fatalf("claimBlob for material two: %v", err)
+	}
+
+	res, err := db.Exec(`INSERT INTO material (subject_id, title, logical_type, file_path, original_name, created_at, content_hash)
+		VALUES ('math-101', 'one', 'doc', 'local://a/one.bin', 'one.bin', '2026-01-01T00:00:00Z', 'hash1')`)
+	if err != nil {
+		t.Fatalf("insert material: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatalf("last insert id: %v", err)
+	}
+
+	if err := deleteMaterial(id); err != nil {
+		t.Fatalf("first deleteMaterial: %v", err)
+	}
+	if err := deleteMaterial(id); err != nil {
+		t.Fatalf("second (retried) deleteMaterial: %v", err)
+	}
+
+	if _, refCount, err := blobRefCount("hash1"); err != nil {
+		t.Fatalf("blobRefCount: %v", err)
+	} else if refCount != 1 {
+		t.Fatalf("ref_count = %d, want 1: a retried delete of the same id must not release the blob twice", refCount)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+)
+
+// ensureBlobTable creates the reference-counted blob table used for
+// content-addressed deduplication.
+func ensureBlobTable() error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS material_blob (
+		content_hash TEXT PRIMARY KEY,
+		file_path TEXT NOT NULL,
+		ref_count INTEGER NOT NULL DEFAULT 0
+	);`)
+	return err
+}
+
+// blobPathForHash returns the already-stored path for a content hash, if any.
+func blobPathForHash(hash string) (string, bool, error) {
+	var path string
+	row := db.QueryRow("SELECT file_path FROM material_blob WHERE content_hash = ?", hash)
+	err := row.Scan(&path)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return path, true, nil
+}
+
+// claimBlob atomically claims hash for storedPath: if no row exists yet for
+// hash, it inserts one and reports won=true, so the caller knows storedPath
+// is now the canonical copy. If another call already claimed hash first, it
+// instead bumps that row's ref_count and reports the path the caller should
+// use in place of storedPath (won=false), so the caller can discard its own
+// just-written copy as a duplicate instead of leaking it.
+//
+// Doing the check and the insert as one statement (rather than a separate
+// blobPathForHash lookup followed by an insert) closes the race where two
+// concurrent uploads of identical content both see "not found" and both try
+// to register themselves as the canonical blob.
+func claimBlob(hash, storedPath string) (canonicalPath string, won bool, err error) {
+	res, err := db.Exec(`INSERT INTO material_blob (content_hash, file_path, ref_count) VALUES (?, ?, 1)
+		ON CONFLICT(content_hash) DO NOTHING`, hash, storedPath)
+	if err != nil {
+		return "", false, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return "", false, err
+	} else if n > 0 {
+		return storedPath, true, nil
+	}
+
+	// Lost the race: a row for this hash already exists. Bump its ref count
+	// and reuse its path instead of the one we just wrote.
+	res, err = db.Exec(`UPDATE material_blob SET ref_count = ref_count + 1 WHERE content_hash = ?`, hash)
+	if err != nil {
+		return "", false, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return "", false, err
+	} else if n == 0 {
+		// The row was deleted (ref_count hit zero) between our failed insert
+		// and this update; retry the claim from scratch.
+		return claimBlob(hash, storedPath)
+	}
+
+	existingPath, ok, err := blobPathForHash(hash)
+	if err != nil {
+		return "", false, err
+	}
+	if !ok {
+		return claimBlob(hash, storedPath)
+	}
+	return existingPath, false, nil
+}
+
+// releaseBlob decrements the reference count for hash and reports whether it
+// reached zero, meaning the underlying file is now safe to delete.
+func releaseBlob(hash string) (path string, canDelete bool, err error) {
+	row := db.QueryRow("SELECT file_path, ref_count FROM material_blob WHERE content_hash = ?", hash)
+	var refCount int
+	if err := row.Scan(&path, &refCount); err != nil {
+		return "", false, err
+	}
+
+	refCount--
+	if refCount <= 0 {
+		if _, err := db.Exec("DELETE FROM material_blob WHERE content_hash = ?", hash); err != nil {
+			return "", false, err
+		}
+		return path, true, nil
+	}
+
+	_, err = db.Exec("UPDATE material_blob SET ref_count = ? WHERE content_hash = ?", refCount, hash)
+	return path, false, err
+}
+
+// saveDeduped streams r through provider while hashing it with SHA-256. If
+// the resulting hash already has a blob on record, the just-written copy is
+// discarded and the existing (scheme-prefixed) path is reused instead. The
+// returned path always carries its provider:// scheme, same as
+// material.file_path, so releaseBlob can resolve it back to a provider.
+func saveDeduped(ctx context.Context, provider StorageProvider, scheme, subjectID, filename string, r io.Reader) (storedPath, hash string, err error) {
+	hasher := sha256.New()
+	relPath, err := provider.Save(ctx, subjectID, filename, io.TeeReader(r, hasher))
+	if err != nil {
+		return "", "", err
+	}
+	hash = hex.EncodeToString(hasher.Sum(nil))
+	storedPath = withScheme(scheme, relPath)
+
+	canonicalPath, won, err := claimBlob(hash, storedPath)
+	if err != nil {
+		return "", "", err
+	}
+	if !won {
+		_ = provider.Delete(relPath)
+	}
+	return canonicalPath, hash, nil
+}
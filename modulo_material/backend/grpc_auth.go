@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"domotica/modulo_material/backend/auth"
+)
+
+type grpcAuthKeyCtxKey struct{}
+
+// authKeyFromIncoming extracts the bearer token from the call's incoming
+// "authorization" metadata and validates it the same way auth.RequireValidKey
+// does for REST; gRPC has no *gin.Context to read a header from.
+func authKeyFromIncoming(ctx context.Context) (auth.Key, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return auth.Key{}, status.Error(codes.Unauthenticated, "missing auth token")
+	}
+
+	var token string
+	for _, v := range md.Get("authorization") {
+		if strings.HasPrefix(v, "Bearer ") {
+			token = strings.TrimPrefix(v, "Bearer ")
+			break
+		}
+	}
+
+	k, ok := auth.AuthenticateToken(db, token)
+	if !ok {
+		return auth.Key{}, status.Error(codes.Unauthenticated, "invalid or expired auth token")
+	}
+	return k, nil
+}
+
+// grpcUnaryAuthInterceptor requires a valid API key on every unary
+// MaterialService call, mirroring auth.RequireValidKey for REST.
+func grpcUnaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	k, err := authKeyFromIncoming(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, grpcAuthKeyCtxKey{}, k), req)
+}
+
+// grpcStreamAuthInterceptor does the same for the streaming Upload/Download
+// RPCs, stashing the authenticated key on the stream's context so handlers
+// can enforce subject-level ACLs once they know the subject_id (e.g. Upload,
+// once it has read its first message).
+func grpcStreamAuthInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	k, err := authKeyFromIncoming(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedServerStream{ServerStream: ss, key: k})
+}
+
+// authenticatedServerStream wraps a grpc.ServerStream so its Context carries
+// the key the interceptor already authenticated.
+type authenticatedServerStream struct {
+	grpc.ServerStream
+	key auth.Key
+}
+
+func (s *authenticatedServerStream) Context() context.Context {
+	return context.WithValue(s.ServerStream.Context(), grpcAuthKeyCtxKey{}, s.key)
+}
+
+// grpcAuthKeyFromContext returns the API key authenticated by the
+// interceptors above.
+func grpcAuthKeyFromContext(ctx context.Context) (auth.Key, bool) {
+	k, ok := ctx.Value(grpcAuthKeyCtxKey{}).(auth.Key)
+	return k, ok
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"domotica/modulo_material/backend/internal/pb"
+)
+
+const GRPCPort = ":9090"
+
+// materialServer implements pb.MaterialServiceServer on top of the same db
+// and storage registry used by the REST API.
+type materialServer struct {
+	pb.UnimplementedMaterialServiceServer
+}
+
+func (s *materialServer) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	materials, err := listMaterial()
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.ListResponse{}
+	for _, m := range materials {
+		resp.Materials = append(resp.Materials, toPBMaterial(m))
+	}
+	return resp, nil
+}
+
+func (s *materialServer) GetPathsBySubject(ctx context.Context, req *pb.GetPathsBySubjectRequest) (*pb.GetPathsBySubjectResponse, error) {
+	paths, err := getPathsForSubject(req.SubjectId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetPathsBySubjectResponse{SubjectId: req.SubjectId, Paths: paths}, nil
+}
+
+// Upload receives an initial UploadMetadata message followed by one or more
+// chunk_data messages, writes them to the configured storage provider and
+// inserts the resulting Material row.
+func (s *materialServer) Upload(stream pb.MaterialService_UploadServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	meta := first.GetMetadata()
+	if meta == nil {
+		return fmt.Errorf("grpc upload: first message must carry metadata")
+	}
+
+	// The stream interceptor only guarantees the caller holds a valid key;
+	// it can't know the subject_id until now, so the ACL check (and the
+	// "absent subject_id isn't any subject" rule from RequireSubjectAccess)
+	// has to happen here, before anything is written.
+	k, ok := grpcAuthKeyFromContext(stream.Context())
+	if !ok || meta.SubjectId == "" || !k.Allows(meta.SubjectId) {
+		return status.Error(codes.PermissionDenied, "key not authorized for this subject")
+	}
+
+	ts := time.Now().Unix()
+	safeName := strings.ReplaceAll(meta.OriginalName, " ", "_")
+	storedName := fmt.Sprintf("%d_%s", ts, safeName)
+
+	scheme, provider := storage.providerFor(meta.SubjectId)
+	if provider == nil {
+		return fmt.Errorf("grpc upload: no storage provider configured")
+	}
+	// Stream chunk_data messages straight into the provider as they arrive
+	// instead of buffering the whole upload in memory first.
+	storedPath, hash, err := saveDeduped(context.Background(), provider, scheme, meta.SubjectId, storedName, &uploadStreamReader{stream: stream})
+	if err != nil {
+		return err
+	}
+
+	newID, err := addMaterial(Material{
+		SubjectID:    meta.SubjectId,
+		Title:        meta.Title,
+		LogicalType:  meta.LogicalType,
+		FilePath:     storedPath,
+		OriginalName: meta.OriginalName,
+		MimeType:     meta.MimeType,
+		Description:  meta.Description,
+		CreatedAt:    time.Now().Format(time.RFC3339),
+		ContentHash:  hash,
+	})
+	if err != nil {
+		return err
+	}
+	enqueueProcessing(newID)
+
+	return stream.SendAndClose(&pb.UploadResponse{Id: newID, StoredPath: storedPath})
+}
+
+// uploadStreamReader adapts the chunk_data messages of an upload stream into
+// an io.Reader, so saveDeduped can hash and write them as they arrive
+// instead of requiring the whole file to be buffered in memory up front.
+type uploadStreamReader struct {
+	stream pb.MaterialService_UploadServer
+	rest   []byte
+}
+
+func (r *uploadStreamReader) Read(p []byte) (int, error) {
+	for len(r.rest) == 0 {
+		req, err := r.stream.Recv()
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		r.rest = req.GetChunkData()
+	}
+	n := copy(p, r.rest)
+	r.rest = r.rest[n:]
+	return n, nil
+}
+
+// Download streams the stored file for a material ID back to the client in
+// fixed-size chunks.
+func (s *materialServer) Download(req *pb.DownloadRequest, stream pb.MaterialService_DownloadServer) error {
+	var storedPath string
+	row := db.QueryRow("SELECT file_path FROM material WHERE id = ?", req.Id)
+	if err := row.Scan(&storedPath); err != nil {
+		return err
+	}
+
+	p, rel, err := storage.resolve(storedPath)
+	if err != nil {
+		return err
+	}
+	f, err := p.Open(rel)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&pb.Chunk{Data: append([]byte(nil), buf[:n]...)}); sendErr != nil {
+				return sendErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func toPBMaterial(m Material) *pb.Material {
+	return &pb.Material{
+		Id:           m.ID,
+		SubjectId:    m.SubjectID,
+		Title:        m.Title,
+		LogicalType:  m.LogicalType,
+		FilePath:     m.FilePath,
+		OriginalName: m.OriginalName,
+		MimeType:     m.MimeType,
+		Description:  m.Description,
+		CreatedAt:    m.CreatedAt,
+	}
+}
+
+// startGRPCServer serves MaterialService on GRPCPort next to the Gin HTTP
+// server, sharing the same db and storage registry.
+func startGRPCServer() {
+	lis, err := net.Listen("tcp", GRPCPort)
+	if err != nil {
+		log.Fatalf("grpc: failed to listen: %v", err)
+	}
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(grpcUnaryAuthInterceptor),
+		grpc.StreamInterceptor(grpcStreamAuthInterceptor),
+	)
+	pb.RegisterMaterialServiceServer(s, &materialServer{})
+	fmt.Printf("gRPC server running on %s\n", GRPCPort)
+	if err := s.Serve(lis); err != nil {
+		log.Fatalf("grpc: serve error: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"domotica/modulo_material/backend/auth"
+	"domotica/modulo_material/backend/internal/pb"
+)
+
+// startTestGRPCServer brings up a materialServer behind the same auth
+// interceptors startGRPCServer wires in production, listening on an
+// in-memory bufconn so the test never touches a real port.
+func startTestGRPCServer(t *testing.T) pb.MaterialServiceClient {
+	t.Helper()
+	openTestDB(t)
+
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS material (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		subject_id TEXT NOT NULL,
+		title TEXT NOT NULL,
+		logical_type TEXT NOT NULL,
+		file_path TEXT NOT NULL,
+		original_name TEXT NOT NULL,
+		mime_type TEXT,
+		description TEXT,
+		created_at TEXT NOT NULL,
+		content_hash TEXT
+	);`); err != nil {
+		t.Fatalf("create material table: %v", err)
+	}
+	if err := auth.EnsureTable(db); err != nil {
+		t.Fatalf("ensure auth table: %v", err)
+	}
+	if err := ensureJobTable(); err != nil {
+		t.Fatalf("ensure job table: %v", err)
+	}
+
+	prevStorage := storage
+	reg, err := newStorageRegistry(StorageConfig{DefaultProvider: schemeLocal, Local: LocalStorageConfig{BaseDir: t.TempDir()}})
+	if err != nil {
+		t.Fatalf("new storage registry: %v", err)
+	}
+	storage = reg
+	t.Cleanup(func() { storage = prevStorage })
+
+	prevQueue := jobQueue
+	jobQueue = newChannelQueue(8)
+	t.Cleanup(func() { jobQueue = prevQueue })
+
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(grpcUnaryAuthInterceptor),
+		grpc.StreamInterceptor(grpcStreamAuthInterceptor),
+	)
+	pb.RegisterMaterialServiceServer(s, &materialServer{})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewMaterialServiceClient(conn)
+}
+
+func withBearer(ctx context.Context, token string) context.Context {
+	if token == "" {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func TestGRPCListRejectsMissingToken(t *testing.T) {
+	client := startTestGRPCServer(t)
+
+	_, err := client.List(context.Background(), &pb.ListRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("List with no token: code = %v, want Unauthenticated", status.Code(err))
+	}
+}
+
+func TestGRPCUploadRejectsUnauthorizedSubject(t *testing.T) {
+	client := startTestGRPCServer(t)
+	k, err := auth.CreateKey(db, "owner", []string{"math-101"}, 0)
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+
+	stream, err := client.Upload(withBearer(context.Background(), k.Key))
+	if err != nil {
+		t.Fatalf("open upload stream: %v", err)
+	}
+	if err := stream.Send(&pb.UploadRequest{Payload: &pb.UploadRequest_Metadata{Metadata: &pb.UploadMetadata{
+		SubjectId: "history-301", Title: "t", OriginalName: "f.txt",
+	}}}); err != nil {
+		t.Fatalf("send metadata: %v", err)
+	}
+	if err := stream.Send(&pb.UploadRequest{Payload: &pb.UploadRequest_ChunkData{ChunkData: []byte("hello")}}); err != nil {
+		t.Fatalf("send chunk: %v", err)
+	}
+	_, err = stream.CloseAndRecv()
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Upload to an unauthorized subject: code = %v, want PermissionDenied", status.Code(err))
+	}
+}
+
+func TestGRPCUploadStreamsIntoAllowedSubject(t *testing.T) {
+	client := startTestGRPCServer(t)
+	k, err := auth.CreateKey(db, "owner", []string{"math-101"}, 0)
+	if err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+
+	stream, err := client.Upload(withBearer(context.Background(), k.Key))
+	if err != nil {
+		t.Fatalf("open upload stream: %v", err)
+	}
+	if err := stream.Send(&pb.UploadRequest{Payload: &pb.UploadRequest_Metadata{Metadata: &pb.UploadMetadata{
+		SubjectId: "math-101", Title: "t", OriginalName: "f.txt",
+	}}}); err != nil {
+		t.Fatalf("send metadata: %v", err)
+	}
+	for _, chunk := range []string{"hello ", "world"} {
+		if err := stream.Send(&pb.UploadRequest{Payload: &pb.UploadRequest_ChunkData{ChunkData: []byte(chunk)}}); err != nil {
+			t.Fatalf("send chunk: %v", err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv: %v", err)
+	}
+	if resp.Id == 0 {
+		t.Fatal("expected a non-zero material id")
+	}
+
+	m, ok, err := getMaterialByID(resp.Id)
+	if err != nil || !ok {
+		t.Fatalf("getMaterialByID(%d): ok=%v err=%v", resp.Id, ok, err)
+	}
+	if m.SubjectID != "math-101" {
+		t.Fatalf("stored subject_id = %q, want math-101", m.SubjectID)
+	}
+}
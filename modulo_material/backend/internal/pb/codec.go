@@ -0,0 +1,42 @@
+package pb
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// wireMessage is implemented by every message in this package via its
+// hand-written Marshal/Unmarshal (see wire.go and material.pb.go).
+type wireMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal(data []byte) error
+}
+
+// wireCodec overrides grpc-go's default "proto" codec, which expects
+// google.golang.org/protobuf's reflection-based proto.Message. Our message
+// types don't implement that (no generated ProtoReflect support), so this
+// codec dispatches to their Marshal/Unmarshal methods instead.
+type wireCodec struct{}
+
+func (wireCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(wireMessage)
+	if !ok {
+		return nil, fmt.Errorf("pb: %T does not implement wireMessage", v)
+	}
+	return m.Marshal()
+}
+
+func (wireCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(wireMessage)
+	if !ok {
+		return fmt.Errorf("pb: %T does not implement wireMessage", v)
+	}
+	return m.Unmarshal(data)
+}
+
+func (wireCodec) Name() string { return "proto" }
+
+func init() {
+	encoding.RegisterCodec(wireCodec{})
+}
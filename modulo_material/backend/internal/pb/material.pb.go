@@ -0,0 +1,336 @@
+// Generated from proto/material.proto.
+//
+// NOTE: this is hand-maintained, not protoc output. protoc / protoc-gen-go
+// aren't available in this repo's build environment yet, so the message
+// types below implement the protobuf wire format themselves (see wire.go)
+// instead of relying on generated ProtoReflect support. Keep these in sync
+// with proto/material.proto by hand, and swap this for real `protoc
+// --go_out` generation once the toolchain is wired into CI.
+
+package pb
+
+type Material struct {
+	Id           int64  `json:"id,omitempty"`
+	SubjectId    string `json:"subject_id,omitempty"`
+	Title        string `json:"title,omitempty"`
+	LogicalType  string `json:"logical_type,omitempty"`
+	FilePath     string `json:"file_path,omitempty"`
+	OriginalName string `json:"original_name,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+	Description  string `json:"description,omitempty"`
+	CreatedAt    string `json:"created_at,omitempty"`
+}
+
+func (m *Material) Marshal() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	var buf []byte
+	buf = appendVarintField(buf, 1, m.Id)
+	buf = appendStringField(buf, 2, m.SubjectId)
+	buf = appendStringField(buf, 3, m.Title)
+	buf = appendStringField(buf, 4, m.LogicalType)
+	buf = appendStringField(buf, 5, m.FilePath)
+	buf = appendStringField(buf, 6, m.OriginalName)
+	buf = appendStringField(buf, 7, m.MimeType)
+	buf = appendStringField(buf, 8, m.Description)
+	buf = appendStringField(buf, 9, m.CreatedAt)
+	return buf, nil
+}
+
+func (m *Material) Unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			m.Id = int64(f.varint)
+		case 2:
+			m.SubjectId = string(f.bytes)
+		case 3:
+			m.Title = string(f.bytes)
+		case 4:
+			m.LogicalType = string(f.bytes)
+		case 5:
+			m.FilePath = string(f.bytes)
+		case 6:
+			m.OriginalName = string(f.bytes)
+		case 7:
+			m.MimeType = string(f.bytes)
+		case 8:
+			m.Description = string(f.bytes)
+		case 9:
+			m.CreatedAt = string(f.bytes)
+		}
+	}
+	return nil
+}
+
+type ListRequest struct{}
+
+func (m *ListRequest) Marshal() ([]byte, error)    { return nil, nil }
+func (m *ListRequest) Unmarshal(data []byte) error { return nil }
+
+type ListResponse struct {
+	Materials []*Material `json:"materials,omitempty"`
+}
+
+func (m *ListResponse) Marshal() ([]byte, error) {
+	var buf []byte
+	var err error
+	for _, mat := range m.Materials {
+		if buf, err = appendMessageField(buf, 1, mat); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func (m *ListResponse) Unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.num != 1 {
+			continue
+		}
+		mat := &Material{}
+		if err := mat.Unmarshal(f.bytes); err != nil {
+			return err
+		}
+		m.Materials = append(m.Materials, mat)
+	}
+	return nil
+}
+
+type GetPathsBySubjectRequest struct {
+	SubjectId string `json:"subject_id,omitempty"`
+}
+
+func (m *GetPathsBySubjectRequest) Marshal() ([]byte, error) {
+	return appendStringField(nil, 1, m.SubjectId), nil
+}
+
+func (m *GetPathsBySubjectRequest) Unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.num == 1 {
+			m.SubjectId = string(f.bytes)
+		}
+	}
+	return nil
+}
+
+type GetPathsBySubjectResponse struct {
+	SubjectId string   `json:"subject_id,omitempty"`
+	Paths     []string `json:"paths,omitempty"`
+}
+
+func (m *GetPathsBySubjectResponse) Marshal() ([]byte, error) {
+	buf := appendStringField(nil, 1, m.SubjectId)
+	for _, p := range m.Paths {
+		buf = appendStringField(buf, 2, p)
+	}
+	return buf, nil
+}
+
+func (m *GetPathsBySubjectResponse) Unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			m.SubjectId = string(f.bytes)
+		case 2:
+			m.Paths = append(m.Paths, string(f.bytes))
+		}
+	}
+	return nil
+}
+
+type UploadMetadata struct {
+	SubjectId    string `json:"subject_id,omitempty"`
+	Title        string `json:"title,omitempty"`
+	LogicalType  string `json:"logical_type,omitempty"`
+	Description  string `json:"description,omitempty"`
+	OriginalName string `json:"original_name,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+}
+
+func (m *UploadMetadata) Marshal() ([]byte, error) {
+	var buf []byte
+	buf = appendStringField(buf, 1, m.SubjectId)
+	buf = appendStringField(buf, 2, m.Title)
+	buf = appendStringField(buf, 3, m.LogicalType)
+	buf = appendStringField(buf, 4, m.Description)
+	buf = appendStringField(buf, 5, m.OriginalName)
+	buf = appendStringField(buf, 6, m.MimeType)
+	return buf, nil
+}
+
+func (m *UploadMetadata) Unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			m.SubjectId = string(f.bytes)
+		case 2:
+			m.Title = string(f.bytes)
+		case 3:
+			m.LogicalType = string(f.bytes)
+		case 4:
+			m.Description = string(f.bytes)
+		case 5:
+			m.OriginalName = string(f.bytes)
+		case 6:
+			m.MimeType = string(f.bytes)
+		}
+	}
+	return nil
+}
+
+// UploadRequest_Payload is the oneof interface implemented by
+// UploadRequest_Metadata and UploadRequest_ChunkData.
+type UploadRequest_Payload interface {
+	isUploadRequest_Payload()
+}
+
+type UploadRequest_Metadata struct {
+	Metadata *UploadMetadata
+}
+
+type UploadRequest_ChunkData struct {
+	ChunkData []byte
+}
+
+func (*UploadRequest_Metadata) isUploadRequest_Payload()  {}
+func (*UploadRequest_ChunkData) isUploadRequest_Payload() {}
+
+type UploadRequest struct {
+	Payload UploadRequest_Payload
+}
+
+func (m *UploadRequest) GetMetadata() *UploadMetadata {
+	if md, ok := m.Payload.(*UploadRequest_Metadata); ok {
+		return md.Metadata
+	}
+	return nil
+}
+
+func (m *UploadRequest) GetChunkData() []byte {
+	if cd, ok := m.Payload.(*UploadRequest_ChunkData); ok {
+		return cd.ChunkData
+	}
+	return nil
+}
+
+func (m *UploadRequest) Marshal() ([]byte, error) {
+	switch p := m.Payload.(type) {
+	case *UploadRequest_Metadata:
+		return appendMessageField(nil, 1, p.Metadata)
+	case *UploadRequest_ChunkData:
+		return appendBytesFieldAlways(nil, 2, p.ChunkData), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *UploadRequest) Unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			md := &UploadMetadata{}
+			if err := md.Unmarshal(f.bytes); err != nil {
+				return err
+			}
+			m.Payload = &UploadRequest_Metadata{Metadata: md}
+		case 2:
+			m.Payload = &UploadRequest_ChunkData{ChunkData: append([]byte(nil), f.bytes...)}
+		}
+	}
+	return nil
+}
+
+type UploadResponse struct {
+	Id         int64  `json:"id,omitempty"`
+	StoredPath string `json:"stored_path,omitempty"`
+}
+
+func (m *UploadResponse) Marshal() ([]byte, error) {
+	buf := appendVarintField(nil, 1, m.Id)
+	buf = appendStringField(buf, 2, m.StoredPath)
+	return buf, nil
+}
+
+func (m *UploadResponse) Unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			m.Id = int64(f.varint)
+		case 2:
+			m.StoredPath = string(f.bytes)
+		}
+	}
+	return nil
+}
+
+type DownloadRequest struct {
+	Id int64 `json:"id,omitempty"`
+}
+
+func (m *DownloadRequest) Marshal() ([]byte, error) {
+	return appendVarintField(nil, 1, m.Id), nil
+}
+
+func (m *DownloadRequest) Unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.num == 1 {
+			m.Id = int64(f.varint)
+		}
+	}
+	return nil
+}
+
+type Chunk struct {
+	Data []byte `json:"data,omitempty"`
+}
+
+func (m *Chunk) Marshal() ([]byte, error) {
+	return appendBytesField(nil, 1, m.Data), nil
+}
+
+func (m *Chunk) Unmarshal(data []byte) error {
+	fields, err := parseFields(data)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.num == 1 {
+			m.Data = append([]byte(nil), f.bytes...)
+		}
+	}
+	return nil
+}
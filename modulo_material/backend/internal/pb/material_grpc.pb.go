@@ -0,0 +1,250 @@
+// Generated from proto/material.proto.
+//
+// NOTE: this is hand-maintained, not protoc-gen-go-grpc output, for the
+// same reason as material.pb.go: no protoc toolchain in this repo's build
+// environment yet. The service descriptor, handlers and client below match
+// what protoc-gen-go-grpc would emit; codec.go registers the "proto" codec
+// that (de)serializes via each message's hand-written Marshal/Unmarshal
+// instead of the reflection-based default.
+
+package pb
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MaterialServiceClient is the client API for MaterialService.
+type MaterialServiceClient interface {
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	GetPathsBySubject(ctx context.Context, in *GetPathsBySubjectRequest, opts ...grpc.CallOption) (*GetPathsBySubjectResponse, error)
+	Upload(ctx context.Context, opts ...grpc.CallOption) (MaterialService_UploadClient, error)
+	Download(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (MaterialService_DownloadClient, error)
+}
+
+type materialServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMaterialServiceClient(cc grpc.ClientConnInterface) MaterialServiceClient {
+	return &materialServiceClient{cc}
+}
+
+func (c *materialServiceClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	out := new(ListResponse)
+	if err := c.cc.Invoke(ctx, "/material.MaterialService/List", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *materialServiceClient) GetPathsBySubject(ctx context.Context, in *GetPathsBySubjectRequest, opts ...grpc.CallOption) (*GetPathsBySubjectResponse, error) {
+	out := new(GetPathsBySubjectResponse)
+	if err := c.cc.Invoke(ctx, "/material.MaterialService/GetPathsBySubject", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *materialServiceClient) Upload(ctx context.Context, opts ...grpc.CallOption) (MaterialService_UploadClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MaterialService_ServiceDesc.Streams[0], "/material.MaterialService/Upload", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &materialServiceUploadClient{stream}, nil
+}
+
+func (c *materialServiceClient) Download(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (MaterialService_DownloadClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MaterialService_ServiceDesc.Streams[1], "/material.MaterialService/Download", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &materialServiceDownloadClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MaterialService_UploadClient interface {
+	Send(*UploadRequest) error
+	CloseAndRecv() (*UploadResponse, error)
+	grpc.ClientStream
+}
+
+type materialServiceUploadClient struct {
+	grpc.ClientStream
+}
+
+func (x *materialServiceUploadClient) Send(m *UploadRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *materialServiceUploadClient) CloseAndRecv() (*UploadResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type MaterialService_DownloadClient interface {
+	Recv() (*Chunk, error)
+	grpc.ClientStream
+}
+
+type materialServiceDownloadClient struct {
+	grpc.ClientStream
+}
+
+func (x *materialServiceDownloadClient) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MaterialServiceServer is the server API for MaterialService.
+type MaterialServiceServer interface {
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	GetPathsBySubject(context.Context, *GetPathsBySubjectRequest) (*GetPathsBySubjectResponse, error)
+	Upload(MaterialService_UploadServer) error
+	Download(*DownloadRequest, MaterialService_DownloadServer) error
+}
+
+// UnimplementedMaterialServiceServer can be embedded to have forward
+// compatible implementations.
+type UnimplementedMaterialServiceServer struct{}
+
+func (UnimplementedMaterialServiceServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedMaterialServiceServer) GetPathsBySubject(context.Context, *GetPathsBySubjectRequest) (*GetPathsBySubjectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPathsBySubject not implemented")
+}
+func (UnimplementedMaterialServiceServer) Upload(MaterialService_UploadServer) error {
+	return status.Errorf(codes.Unimplemented, "method Upload not implemented")
+}
+func (UnimplementedMaterialServiceServer) Download(*DownloadRequest, MaterialService_DownloadServer) error {
+	return status.Errorf(codes.Unimplemented, "method Download not implemented")
+}
+
+type MaterialService_UploadServer interface {
+	SendAndClose(*UploadResponse) error
+	Recv() (*UploadRequest, error)
+	grpc.ServerStream
+}
+
+type materialServiceUploadServer struct {
+	grpc.ServerStream
+}
+
+func (x *materialServiceUploadServer) SendAndClose(m *UploadResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *materialServiceUploadServer) Recv() (*UploadRequest, error) {
+	m := new(UploadRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type MaterialService_DownloadServer interface {
+	Send(*Chunk) error
+	grpc.ServerStream
+}
+
+type materialServiceDownloadServer struct {
+	grpc.ServerStream
+}
+
+func (x *materialServiceDownloadServer) Send(m *Chunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _MaterialService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MaterialServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/material.MaterialService/List"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MaterialServiceServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MaterialService_GetPathsBySubject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPathsBySubjectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MaterialServiceServer).GetPathsBySubject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/material.MaterialService/GetPathsBySubject"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MaterialServiceServer).GetPathsBySubject(ctx, req.(*GetPathsBySubjectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MaterialService_Upload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MaterialServiceServer).Upload(&materialServiceUploadServer{stream})
+}
+
+func _MaterialService_Download_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MaterialServiceServer).Download(m, &materialServiceDownloadServer{stream})
+}
+
+var MaterialService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "material.MaterialService",
+	HandlerType: (*MaterialServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _MaterialService_List_Handler,
+		},
+		{
+			MethodName: "GetPathsBySubject",
+			Handler:    _MaterialService_GetPathsBySubject_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Upload",
+			Handler:       _MaterialService_Upload_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Download",
+			Handler:       _MaterialService_Download_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/material.proto",
+}
+
+// RegisterMaterialServiceServer registers srv with the gRPC server s.
+func RegisterMaterialServiceServer(s grpc.ServiceRegistrar, srv MaterialServiceServer) {
+	s.RegisterService(&MaterialService_ServiceDesc, srv)
+}
@@ -0,0 +1,114 @@
+package pb
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// wire.go implements just enough of the protobuf wire format (varints,
+// length-delimited fields) for the messages in this package to round-trip
+// over gRPC. The repo has no protoc/protoc-gen-go toolchain available in
+// CI, so these Marshal/Unmarshal methods are maintained by hand instead of
+// generated; keep them in sync with proto/material.proto by hand too.
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func appendTag(buf []byte, fieldNum, wireType int) []byte {
+	return binary.AppendUvarint(buf, uint64(fieldNum)<<3|uint64(wireType))
+}
+
+func appendVarintField(buf []byte, fieldNum int, v int64) []byte {
+	if v == 0 {
+		return buf
+	}
+	buf = appendTag(buf, fieldNum, wireVarint)
+	return binary.AppendUvarint(buf, uint64(v))
+}
+
+func appendBytesField(buf []byte, fieldNum int, v []byte) []byte {
+	if len(v) == 0 {
+		return buf
+	}
+	buf = appendTag(buf, fieldNum, wireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(v)))
+	return append(buf, v...)
+}
+
+func appendStringField(buf []byte, fieldNum int, v string) []byte {
+	if v == "" {
+		return buf
+	}
+	return appendBytesField(buf, fieldNum, []byte(v))
+}
+
+// appendBytesFieldAlways writes v even when empty. Used for oneof fields,
+// where proto3 tracks explicit presence regardless of the zero value.
+func appendBytesFieldAlways(buf []byte, fieldNum int, v []byte) []byte {
+	buf = appendTag(buf, fieldNum, wireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(v)))
+	return append(buf, v...)
+}
+
+// appendMessageField always writes the field, even if marshaling m produces
+// zero bytes, since an empty embedded message is still "present".
+func appendMessageField(buf []byte, fieldNum int, m interface{ Marshal() ([]byte, error) }) ([]byte, error) {
+	enc, err := m.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	buf = appendTag(buf, fieldNum, wireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(enc)))
+	return append(buf, enc...), nil
+}
+
+// wireField is one decoded (fieldNum, wireType, raw-bytes-or-varint) entry
+// from an Unmarshal pass.
+type wireField struct {
+	num     int
+	varint  uint64
+	bytes   []byte
+	isBytes bool
+}
+
+// parseFields walks data once, splitting it into its top-level fields. Each
+// message's Unmarshal then dispatches on num/isBytes itself; this keeps the
+// decoding loop in one place instead of duplicated per message.
+func parseFields(data []byte) ([]wireField, error) {
+	var fields []wireField
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("pb: invalid tag")
+		}
+		data = data[n:]
+		fieldNum := int(tag >> 3)
+		wireType := int(tag & 7)
+
+		switch wireType {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("pb: invalid varint for field %d", fieldNum)
+			}
+			data = data[n:]
+			fields = append(fields, wireField{num: fieldNum, varint: v})
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("pb: invalid length for field %d", fieldNum)
+			}
+			data = data[n:]
+			if uint64(len(data)) < length {
+				return nil, fmt.Errorf("pb: truncated field %d", fieldNum)
+			}
+			fields = append(fields, wireField{num: fieldNum, bytes: data[:length], isBytes: true})
+			data = data[length:]
+		default:
+			return nil, fmt.Errorf("pb: unsupported wire type %d for field %d", wireType, fieldNum)
+		}
+	}
+	return fields, nil
+}
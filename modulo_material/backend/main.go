@@ -3,25 +3,30 @@ package main
 import (
 	"database/sql"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	_ "github.com/mattn/go-sqlite3"
+
+	"domotica/modulo_material/backend/auth"
 )
 
 const (
-	Port     = ":8080"
-	FilesDir = "./material_files"
-	DBPath   = "./materials.db"
+	Port              = ":8080"
+	FilesDir          = "./material_files"
+	DBPath            = "./materials.db"
+	StorageConfigPath = "./storage_config.yaml"
 )
 
 var db *sql.DB
+var storage *storageRegistry
 
 type Material struct {
 	ID           int64  `json:"id"`
@@ -33,6 +38,7 @@ type Material struct {
 	MimeType     string `json:"mime_type"`
 	Description  string `json:"description"`
 	CreatedAt    string `json:"created_at"`
+	ContentHash  string `json:"content_hash"`
 }
 
 func initDB() {
@@ -51,17 +57,27 @@ func initDB() {
 		original_name TEXT NOT NULL,
 		mime_type TEXT,
 		description TEXT,
-		created_at TEXT NOT NULL
+		created_at TEXT NOT NULL,
+		content_hash TEXT
 	);`
 
 	_, err = db.Exec(createTableSQL)
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	// Migra bases de datos creadas antes de la columna content_hash; se ignora
+	// el error si la columna ya existe.
+	_, _ = db.Exec(`ALTER TABLE material ADD COLUMN content_hash TEXT`)
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_material_content_hash ON material(content_hash)`)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func listMaterial() ([]Material, error) {
-	rows, err := db.Query("SELECT id, subject_id, title, logical_type, file_path, original_name, mime_type, description, created_at FROM material ORDER BY created_at DESC")
+	rows, err := db.Query("SELECT id, subject_id, title, logical_type, file_path, original_name, mime_type, description, created_at, content_hash FROM material ORDER BY created_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -70,8 +86,8 @@ func listMaterial() ([]Material, error) {
 	var materials []Material
 	for rows.Next() {
 		var m Material
-		var mimeType, description sql.NullString
-		err = rows.Scan(&m.ID, &m.SubjectID, &m.Title, &m.LogicalType, &m.FilePath, &m.OriginalName, &mimeType, &description, &m.CreatedAt)
+		var mimeType, description, contentHash sql.NullString
+		err = rows.Scan(&m.ID, &m.SubjectID, &m.Title, &m.LogicalType, &m.FilePath, &m.OriginalName, &mimeType, &description, &m.CreatedAt, &contentHash)
 		if err != nil {
 			return nil, err
 		}
@@ -81,19 +97,22 @@ func listMaterial() ([]Material, error) {
 		if description.Valid {
 			m.Description = description.String
 		}
+		if contentHash.Valid {
+			m.ContentHash = contentHash.String
+		}
 		materials = append(materials, m)
 	}
 	return materials, nil
 }
 
 func addMaterial(m Material) (int64, error) {
-	stmt, err := db.Prepare("INSERT INTO material (subject_id, title, logical_type, file_path, original_name, mime_type, description, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	stmt, err := db.Prepare("INSERT INTO material (subject_id, title, logical_type, file_path, original_name, mime_type, description, created_at, content_hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return 0, err
 	}
 	defer stmt.Close()
 
-	res, err := stmt.Exec(m.SubjectID, m.Title, m.LogicalType, m.FilePath, m.OriginalName, m.MimeType, m.Description, m.CreatedAt)
+	res, err := stmt.Exec(m.SubjectID, m.Title, m.LogicalType, m.FilePath, m.OriginalName, m.MimeType, m.Description, m.CreatedAt, m.ContentHash)
 	if err != nil {
 		return 0, err
 	}
@@ -124,6 +143,39 @@ func main() {
 	if err := os.MkdirAll(FilesDir, 0755); err != nil {
 		log.Fatal(err)
 	}
+	if err := os.MkdirAll(UploadSessionsDir, 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	storageCfg, err := loadStorageConfig(StorageConfigPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	storage, err = newStorageRegistry(storageCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := auth.EnsureTable(db); err != nil {
+		log.Fatal(err)
+	}
+	if err := ensureBlobTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := ensureUploadSessionTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := ensureJobTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := ensureFTSTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := ensureSearchFTS(); err != nil {
+		log.Fatal(err)
+	}
+	initQueue(os.Getenv("MATERIAL_REDIS_ADDR"), 4)
+	masterKey := os.Getenv("MATERIAL_MASTER_KEY")
 
 	r := gin.Default()
 
@@ -136,7 +188,19 @@ func main() {
 		AllowCredentials: true,
 	}))
 
-	r.Static("/material_files", FilesDir) // Servir archivos estáticos si es necesario
+	// Servir los archivos que viven en el proveedor local; S3/B2 se sirven
+	// directamente desde su URL pública (ver StorageProvider.URL).
+	r.GET("/material_files/*filepath", func(c *gin.Context) {
+		rel := strings.TrimPrefix(c.Param("filepath"), "/")
+		f, err := storage.providers[schemeLocal].Open(rel)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+			return
+		}
+		defer f.Close()
+		c.Status(http.StatusOK)
+		io.Copy(c.Writer, f)
+	})
 
 	api := r.Group("/api")
 	{
@@ -166,7 +230,25 @@ func main() {
 			c.JSON(http.StatusOK, gin.H{"subject_id": subjectID, "paths": paths})
 		})
 
-		api.POST("/material/upload", func(c *gin.Context) {
+		api.POST("/auth/keys", auth.RequireMasterKey(masterKey), func(c *gin.Context) {
+			var req struct {
+				Owner           string   `json:"owner"`
+				AllowedSubjects []string `json:"allowed_subjects"`
+				TTLSeconds      int64    `json:"ttl_seconds"`
+			}
+			if err := c.BindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+				return
+			}
+			k, err := auth.CreateKey(db, req.Owner, req.AllowedSubjects, time.Duration(req.TTLSeconds)*time.Second)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, k)
+		})
+
+		api.POST("/material/upload", auth.RequireSubjectAccess(db), func(c *gin.Context) {
 			subjectID := c.PostForm("subject_id")
 			title := c.PostForm("title")
 			logicalType := c.PostForm("logical_type")
@@ -178,19 +260,26 @@ func main() {
 				return
 			}
 
-			// Crear directorio para la asignatura
-			subjectDir := filepath.Join(FilesDir, strings.TrimSpace(subjectID))
-			if err := os.MkdirAll(subjectDir, 0755); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subject directory"})
+			src, err := file.Open()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open upload"})
 				return
 			}
+			defer src.Close()
 
 			ts := time.Now().Unix()
 			safeName := strings.ReplaceAll(file.Filename, " ", "_")
-			destPath := filepath.Join(subjectDir, fmt.Sprintf("%d_%s", ts, safeName))
+			storedName := fmt.Sprintf("%d_%s", ts, safeName)
 
-			// Guardar archivo
-			if err := c.SaveUploadedFile(file, destPath); err != nil {
+			// Guardar archivo (deduplicado por contenido) a través del proveedor
+			// configurado para la asignatura
+			scheme, provider := storage.providerFor(strings.TrimSpace(subjectID))
+			if provider == nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "No storage provider configured"})
+				return
+			}
+			storedPath, hash, err := saveDeduped(c.Request.Context(), provider, scheme, subjectID, storedName, src)
+			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
 				return
 			}
@@ -200,11 +289,12 @@ func main() {
 				SubjectID:    strings.TrimSpace(subjectID),
 				Title:        strings.TrimSpace(title),
 				LogicalType:  strings.TrimSpace(logicalType),
-				FilePath:     destPath,
+				FilePath:     storedPath,
 				OriginalName: file.Filename,
 				MimeType:     file.Header.Get("Content-Type"),
 				Description:  strings.TrimSpace(description),
 				CreatedAt:    time.Now().Format(time.RFC3339),
+				ContentHash:  hash,
 			})
 
 			if err != nil {
@@ -212,14 +302,38 @@ func main() {
 				return
 			}
 
+			enqueueProcessing(newID)
+
 			c.JSON(http.StatusOK, gin.H{
 				"status":      "ok",
 				"id":          newID,
-				"stored_path": destPath,
+				"stored_path": storedPath,
 			})
 		})
+
+		api.GET("/material/:id/jobs", func(c *gin.Context) {
+			id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid material id"})
+				return
+			}
+			jobs, err := jobsForMaterial(id)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if jobs == nil {
+				jobs = []jobStatus{}
+			}
+			c.JSON(http.StatusOK, jobs)
+		})
+
+		registerUploadRoutes(api)
+		registerCRUDRoutes(api)
 	}
 
+	go startGRPCServer()
+
 	fmt.Printf("Server running on http://127.0.0.1%s\n", Port)
 	r.Run(Port)
 }
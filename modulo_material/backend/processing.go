@@ -0,0 +1,152 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// processJob runs the full post-upload processing chain for a material:
+// MIME sniffing, thumbnail generation, full-text extraction and a
+// virus-scan hook. The first step that returns an error marks the job as
+// "error" and stops the chain; a failed MIME sniff is ignored. Steps with no
+// implementation behind them are skipped and the job ends as "done_partial".
+func processJob(job Job) {
+	updateJobStatus(job.MaterialID, "running", "")
+
+	path, mimeType, err := materialFileInfo(job.MaterialID)
+	if err != nil {
+		updateJobStatus(job.MaterialID, "error", fmt.Sprintf("material not found: %v", err))
+		return
+	}
+
+	p, rel, err := storage.resolve(path)
+	if err != nil {
+		updateJobStatus(job.MaterialID, "error", fmt.Sprintf("storage resolve failed: %v", err))
+		return
+	}
+
+	sniffed, err := sniffMimeType(p, rel)
+	if err == nil && sniffed != "" && sniffed != mimeType {
+		_, _ = db.Exec("UPDATE material SET mime_type = ? WHERE id = ?", sniffed, job.MaterialID)
+		mimeType = sniffed
+	}
+
+	var skipped []string
+
+	if strings.HasPrefix(mimeType, "image/") || mimeType == "application/pdf" {
+		implemented, err := generatePreview(job.MaterialID, p, rel, mimeType)
+		if err != nil {
+			updateJobStatus(job.MaterialID, "error", fmt.Sprintf("preview generation failed: %v", err))
+			return
+		}
+		if !implemented {
+			skipped = append(skipped, "preview generation")
+		}
+	}
+
+	if mimeType == "application/pdf" || mimeType == "application/vnd.openxmlformats-officedocument.wordprocessingml.document" {
+		text, implemented, err := extractText(p, rel, mimeType)
+		if err != nil {
+			updateJobStatus(job.MaterialID, "error", fmt.Sprintf("text extraction failed: %v", err))
+			return
+		}
+		if !implemented {
+			skipped = append(skipped, "text extraction")
+		} else if err := indexFullText(job.MaterialID, text); err != nil {
+			updateJobStatus(job.MaterialID, "error", fmt.Sprintf("fts indexing failed: %v", err))
+			return
+		}
+	}
+
+	implemented, err := scanForViruses(p, rel)
+	if err != nil {
+		updateJobStatus(job.MaterialID, "error", fmt.Sprintf("virus scan failed: %v", err))
+		return
+	}
+	if !implemented {
+		skipped = append(skipped, "virus scan")
+	}
+
+	if len(skipped) > 0 {
+		updateJobStatus(job.MaterialID, "done_partial", "not implemented yet: "+strings.Join(skipped, ", "))
+		return
+	}
+	updateJobStatus(job.MaterialID, "done", "")
+}
+
+func materialFileInfo(materialID int64) (path, mimeType string, err error) {
+	var mt sql.NullString
+	row := db.QueryRow("SELECT file_path, mime_type FROM material WHERE id = ?", materialID)
+	if err := row.Scan(&path, &mt); err != nil {
+		return "", "", err
+	}
+	if mt.Valid {
+		mimeType = mt.String
+	}
+	return path, mimeType, nil
+}
+
+// sniffMimeType reads the first 512 bytes of the stored file and lets the
+// stdlib figure out its actual content type, since browsers and upload
+// clients can't be trusted to send an accurate Content-Type.
+func sniffMimeType(p StorageProvider, rel string) (string, error) {
+	f, err := p.Open(rel)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := f.Read(buf)
+	if err != nil && n == 0 {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// generatePreview is the step for thumbnails/previews of images and the
+// first page of PDFs. No rendering library is wired in: it renders nothing
+// and always reports implemented=false, so callers don't report a job as
+// fully done when nothing was rendered.
+func generatePreview(materialID int64, p StorageProvider, rel, mimeType string) (implemented bool, err error) {
+	// TODO: wire up an image/PDF rendering library once we pick one.
+	return false, nil
+}
+
+// extractText is the step for full-text extraction from PDFs and DOCX files.
+// No extraction library is wired in: it extracts nothing and always reports
+// implemented=false, so an empty result isn't mistaken for "the document
+// has no text".
+func extractText(p StorageProvider, rel, mimeType string) (text string, implemented bool, err error) {
+	// TODO: wire up a PDF/DOCX text extraction library once we pick one.
+	return "", false, nil
+}
+
+// scanForViruses is the integration point for a virus-scan engine (e.g.
+// ClamAV over clamd). No engine is wired in, so it scans nothing and always
+// reports implemented=false; the job isn't reported as done without ever
+// having been scanned.
+func scanForViruses(p StorageProvider, rel string) (implemented bool, err error) {
+	return false, nil
+}
+
+// ensureFTSTable creates the FTS5 virtual table used to index extracted
+// material text for full-text search.
+func ensureFTSTable() error {
+	_, err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS material_fts USING fts5(material_id UNINDEXED, content);`)
+	return err
+}
+
+func indexFullText(materialID int64, content string) error {
+	if content == "" {
+		return nil
+	}
+	_, err := db.Exec(`DELETE FROM material_fts WHERE material_id = ?`, materialID)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`INSERT INTO material_fts (material_id, content) VALUES (?, ?)`, materialID, content)
+	return err
+}
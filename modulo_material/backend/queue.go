@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/mediocregopher/radix/v3"
+)
+
+// Job describes a unit of post-upload processing work for a material.
+type Job struct {
+	MaterialID int64
+}
+
+const redisJobListKey = "material:jobs"
+
+// JobQueue decouples job submission from how jobs are actually transported:
+// Redis when configured, or an in-process channel for single-node setups.
+type JobQueue interface {
+	Enqueue(ctx context.Context, job Job) error
+	Dequeue(ctx context.Context) (Job, error)
+}
+
+// channelQueue is the fallback used when no Redis address is configured.
+type channelQueue struct {
+	ch chan Job
+}
+
+func newChannelQueue(size int) *channelQueue {
+	return &channelQueue{ch: make(chan Job, size)}
+}
+
+func (q *channelQueue) Enqueue(ctx context.Context, job Job) error {
+	q.ch <- job
+	return nil
+}
+
+func (q *channelQueue) Dequeue(ctx context.Context) (Job, error) {
+	select {
+	case job := <-q.ch:
+		return job, nil
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+}
+
+// redisQueue pushes/pops job material IDs from a Redis list, so the queue
+// survives a server restart as long as Redis itself is up.
+type redisQueue struct {
+	pool *radix.Pool
+}
+
+func newRedisQueue(addr string) (*redisQueue, error) {
+	pool, err := radix.NewPool("tcp", addr, 4)
+	if err != nil {
+		return nil, err
+	}
+	return &redisQueue{pool: pool}, nil
+}
+
+func (q *redisQueue) Enqueue(ctx context.Context, job Job) error {
+	return q.pool.Do(radix.Cmd(nil, "LPUSH", redisJobListKey, formatJob(job)))
+}
+
+func (q *redisQueue) Dequeue(ctx context.Context) (Job, error) {
+	var result []string
+	if err := q.pool.Do(radix.Cmd(&result, "BRPOP", redisJobListKey, "1")); err != nil {
+		return Job{}, err
+	}
+	if len(result) < 2 {
+		return Job{}, context.DeadlineExceeded
+	}
+	return parseJob(result[1])
+}
+
+// jobQueue is the process-wide queue, chosen once in main() based on whether
+// a Redis address was configured.
+var jobQueue JobQueue
+
+// ensureJobTable creates the table used to track per-job status so clients
+// can poll progress via GET /api/material/:id/jobs.
+func ensureJobTable() error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS material_job (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		material_id INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		message TEXT,
+		created_at TEXT NOT NULL,
+		updated_at TEXT NOT NULL
+	);`)
+	return err
+}
+
+func initQueue(redisAddr string, workers int) {
+	if redisAddr != "" {
+		q, err := newRedisQueue(redisAddr)
+		if err != nil {
+			log.Printf("queue: could not connect to redis at %s, falling back to in-process queue: %v", redisAddr, err)
+			jobQueue = newChannelQueue(256)
+		} else {
+			jobQueue = q
+		}
+	} else {
+		jobQueue = newChannelQueue(256)
+	}
+
+	for i := 0; i < workers; i++ {
+		go runWorker(jobQueue)
+	}
+}
+
+// enqueueProcessing creates the material_job row and hands the job to the
+// queue. Errors are logged rather than surfaced, since post-upload
+// processing should never block the upload response.
+func enqueueProcessing(materialID int64) {
+	now := time.Now().Format(time.RFC3339)
+	if _, err := db.Exec(`INSERT INTO material_job (material_id, status, message, created_at, updated_at) VALUES (?, 'queued', '', ?, ?)`,
+		materialID, now, now); err != nil {
+		log.Printf("queue: failed to record job for material %d: %v", materialID, err)
+		return
+	}
+	if err := jobQueue.Enqueue(context.Background(), Job{MaterialID: materialID}); err != nil {
+		log.Printf("queue: failed to enqueue job for material %d: %v", materialID, err)
+	}
+}
+
+// runWorker pulls jobs off q and runs the processing chain until the
+// process is torn down.
+func runWorker(q JobQueue) {
+	for {
+		job, err := q.Dequeue(context.Background())
+		if err != nil {
+			continue
+		}
+		processJob(job)
+	}
+}
+
+func updateJobStatus(materialID int64, status, message string) {
+	_, _ = db.Exec(`UPDATE material_job SET status = ?, message = ?, updated_at = ? WHERE material_id = ? AND status != 'done'`,
+		status, message, time.Now().Format(time.RFC3339), materialID)
+}
+
+type jobStatus struct {
+	ID         int64  `json:"id"`
+	MaterialID int64  `json:"material_id"`
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+func jobsForMaterial(materialID int64) ([]jobStatus, error) {
+	rows, err := db.Query(`SELECT id, material_id, status, message, created_at, updated_at FROM material_job WHERE material_id = ? ORDER BY id ASC`, materialID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []jobStatus
+	for rows.Next() {
+		var j jobStatus
+		if err := rows.Scan(&j.ID, &j.MaterialID, &j.Status, &j.Message, &j.CreatedAt, &j.UpdatedAt); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+func formatJob(job Job) string {
+	return strconv.FormatInt(job.MaterialID, 10)
+}
+
+func parseJob(raw string) (Job, error) {
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return Job{}, err
+	}
+	return Job{MaterialID: id}, nil
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"gopkg.in/yaml.v3"
+)
+
+// StorageProvider abstracts where material files actually live, so the rest
+// of the backend never has to know if a file sits on local disk, in an
+// S3-compatible bucket or in Backblaze B2.
+type StorageProvider interface {
+	Save(ctx context.Context, subjectID, filename string, r io.Reader) (path string, err error)
+	Open(path string) (io.ReadCloser, error)
+	Delete(path string) error
+	URL(path string) string
+}
+
+// Scheme prefixes stored in material.file_path so a path can be re-resolved
+// to the right provider after a migration between backends.
+const (
+	schemeLocal = "local"
+	schemeS3    = "s3"
+	schemeB2    = "b2"
+)
+
+// StorageConfig is loaded from a YAML/JSON file at startup and describes the
+// configured providers plus the default provider for each subject.
+type StorageConfig struct {
+	DefaultProvider string             `yaml:"default_provider" json:"default_provider"`
+	SubjectProvider map[string]string  `yaml:"subject_provider" json:"subject_provider"`
+	Local           LocalStorageConfig `yaml:"local" json:"local"`
+	S3              S3StorageConfig    `yaml:"s3" json:"s3"`
+	B2              B2StorageConfig    `yaml:"b2" json:"b2"`
+}
+
+type LocalStorageConfig struct {
+	BaseDir string `yaml:"base_dir" json:"base_dir"`
+}
+
+type S3StorageConfig struct {
+	Bucket    string `yaml:"bucket" json:"bucket"`
+	Region    string `yaml:"region" json:"region"`
+	Endpoint  string `yaml:"endpoint" json:"endpoint"`
+	AccessKey string `yaml:"access_key" json:"access_key"`
+	SecretKey string `yaml:"secret_key" json:"secret_key"`
+}
+
+type B2StorageConfig struct {
+	Bucket         string `yaml:"bucket" json:"bucket"`
+	ApplicationID  string `yaml:"application_id" json:"application_id"`
+	ApplicationKey string `yaml:"application_key" json:"application_key"`
+}
+
+// loadStorageConfig reads the storage config file. If it doesn't exist we
+// fall back to a local-only config so existing deployments keep working.
+func loadStorageConfig(path string) (StorageConfig, error) {
+	cfg := StorageConfig{
+		DefaultProvider: schemeLocal,
+		Local:           LocalStorageConfig{BaseDir: FilesDir},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+
+	if strings.HasSuffix(path, ".json") {
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return cfg, err
+		}
+	} else if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, err
+	}
+
+	if cfg.DefaultProvider == "" {
+		cfg.DefaultProvider = schemeLocal
+	}
+	return cfg, nil
+}
+
+// storageRegistry resolves a scheme to its StorageProvider and knows which
+// provider to use by default for a given subject.
+type storageRegistry struct {
+	providers map[string]StorageProvider
+	cfg       StorageConfig
+}
+
+func newStorageRegistry(cfg StorageConfig) (*storageRegistry, error) {
+	reg := &storageRegistry{providers: map[string]StorageProvider{}, cfg: cfg}
+
+	reg.providers[schemeLocal] = NewLocalStorage(cfg.Local.BaseDir)
+
+	if cfg.S3.Bucket != "" {
+		s3p, err := NewS3Storage(cfg.S3)
+		if err != nil {
+			return nil, fmt.Errorf("storage: init s3 provider: %w", err)
+		}
+		reg.providers[schemeS3] = s3p
+	}
+
+	if cfg.B2.Bucket != "" {
+		reg.providers[schemeB2] = NewB2Storage(cfg.B2)
+	}
+
+	return reg, nil
+}
+
+// providerFor returns the provider configured for subjectID, falling back to
+// the registry's default provider.
+func (r *storageRegistry) providerFor(subjectID string) (scheme string, p StorageProvider) {
+	scheme = r.cfg.DefaultProvider
+	if s, ok := r.cfg.SubjectProvider[subjectID]; ok && s != "" {
+		scheme = s
+	}
+	return scheme, r.providers[scheme]
+}
+
+// resolve parses a stored `provider://path` value and returns the provider
+// and the bare path it understands.
+func (r *storageRegistry) resolve(storedPath string) (StorageProvider, string, error) {
+	scheme, rest, ok := strings.Cut(storedPath, "://")
+	if !ok {
+		// Paths written before this migration have no scheme: treat as local.
+		return r.providers[schemeLocal], storedPath, nil
+	}
+	p, ok := r.providers[scheme]
+	if !ok {
+		return nil, "", fmt.Errorf("storage: unknown provider scheme %q", scheme)
+	}
+	return p, rest, nil
+}
+
+// withScheme prefixes a provider-relative path with its scheme so it can be
+// stored in file_path and resolved later regardless of which provider is
+// configured as default at read time.
+func withScheme(scheme, path string) string {
+	return scheme + "://" + path
+}
+
+// rejectTraversal errors out if s contains a ".." path segment, so a
+// caller-controlled subjectID or filename can never walk a stored path (or
+// an S3/B2 key) outside the directory/prefix it's meant to be scoped to.
+func rejectTraversal(label, s string) error {
+	for _, seg := range strings.FieldsFunc(s, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if seg == ".." {
+			return fmt.Errorf("storage: %s %q contains a path traversal segment", label, s)
+		}
+	}
+	return nil
+}
+
+// containPath joins rel onto baseDir and guarantees the result stays inside
+// baseDir, rejecting any rel (however it was cleaned or encoded) that would
+// otherwise resolve outside it.
+func containPath(baseDir, rel string) (string, error) {
+	full := filepath.Join(baseDir, rel)
+	relToBase, err := filepath.Rel(baseDir, full)
+	if err != nil || relToBase == ".." || strings.HasPrefix(relToBase, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("storage: path %q escapes base directory", rel)
+	}
+	return full, nil
+}
+
+// --- local disk provider (current behaviour, unchanged semantics) ---
+
+type LocalStorage struct {
+	baseDir string
+}
+
+func NewLocalStorage(baseDir string) *LocalStorage {
+	if baseDir == "" {
+		baseDir = FilesDir
+	}
+	return &LocalStorage{baseDir: baseDir}
+}
+
+func (l *LocalStorage) Save(ctx context.Context, subjectID, filename string, r io.Reader) (string, error) {
+	subjectID = strings.TrimSpace(subjectID)
+	if err := rejectTraversal("subject_id", subjectID); err != nil {
+		return "", err
+	}
+	if err := rejectTraversal("filename", filename); err != nil {
+		return "", err
+	}
+
+	destPath, err := containPath(l.baseDir, filepath.Join(subjectID, filename))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return "", err
+	}
+	f, err := os.Create(destPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, r); err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(l.baseDir, destPath)
+	if err != nil {
+		return "", err
+	}
+	return rel, nil
+}
+
+func (l *LocalStorage) Open(path string) (io.ReadCloser, error) {
+	full, err := containPath(l.baseDir, path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(full)
+}
+
+func (l *LocalStorage) Delete(path string) error {
+	full, err := containPath(l.baseDir, path)
+	if err != nil {
+		return err
+	}
+	return os.Remove(full)
+}
+
+func (l *LocalStorage) URL(path string) string {
+	return "/material_files/" + filepath.ToSlash(path)
+}
+
+// --- S3-compatible provider (AWS S3 or MinIO) ---
+
+type S3Storage struct {
+	client *s3.Client
+	bucket string
+}
+
+func NewS3Storage(cfg S3StorageConfig) (*S3Storage, error) {
+	awsCfg := aws.Config{Region: cfg.Region}
+	if cfg.AccessKey != "" || cfg.SecretKey != "" {
+		awsCfg.Credentials = credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, "")
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &S3Storage{client: client, bucket: cfg.Bucket}, nil
+}
+
+func (s *S3Storage) Save(ctx context.Context, subjectID, filename string, r io.Reader) (string, error) {
+	subjectID = strings.TrimSpace(subjectID)
+	if err := rejectTraversal("subject_id", subjectID); err != nil {
+		return "", err
+	}
+	if err := rejectTraversal("filename", filename); err != nil {
+		return "", err
+	}
+
+	key := subjectID + "/" + filename
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   r,
+	})
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func (s *S3Storage) Open(path string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (s *S3Storage) Delete(path string) error {
+	_, err := s.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path),
+	})
+	return err
+}
+
+func (s *S3Storage) URL(path string) string {
+	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", s.bucket, path)
+}
+
+// --- Backblaze B2 provider ---
+//
+// B2 speaks S3-compatible API for buckets created in "S3 Compatible" mode,
+// so we reuse the S3 client against B2's endpoint rather than pulling in a
+// separate SDK.
+
+type B2Storage struct {
+	*S3Storage
+}
+
+func NewB2Storage(cfg B2StorageConfig) *B2Storage {
+	s3p, _ := NewS3Storage(S3StorageConfig{
+		Bucket:    cfg.Bucket,
+		Endpoint:  "https://s3.us-west-002.backblazeb2.com",
+		AccessKey: cfg.ApplicationID,
+		SecretKey: cfg.ApplicationKey,
+	})
+	return &B2Storage{S3Storage: s3p}
+}
+
+func (b *B2Storage) URL(path string) string {
+	return fmt.Sprintf("https://f002.backblazeb2.com/file/%s/%s", b.bucket, path)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorageOpenRejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	ls := NewLocalStorage(dir)
+	if _, err := ls.Open("../../../../etc/passwd"); err == nil {
+		t.Fatal("Open should reject a path that escapes baseDir")
+	}
+}
+
+func TestLocalStorageSaveRejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	ls := NewLocalStorage(dir)
+
+	if _, err := ls.Save(nil, "../escaped-subject", "file.txt", strings.NewReader("x")); err == nil {
+		t.Fatal("Save should reject a subject_id containing a traversal segment")
+	}
+	if _, err := ls.Save(nil, "subject", "../../evil.txt", strings.NewReader("x")); err == nil {
+		t.Fatal("Save should reject a filename containing a traversal segment")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "evil.txt")); err == nil {
+		t.Fatal("a rejected Save must not have written anything outside baseDir")
+	}
+}
+
+func TestLocalStorageDeleteRejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	ls := NewLocalStorage(dir)
+	if err := ls.Delete("../../somewhere/else.txt"); err == nil {
+		t.Fatal("Delete should reject a path that escapes baseDir")
+	}
+}
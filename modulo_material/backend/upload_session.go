@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"domotica/modulo_material/backend/auth"
+)
+
+// UploadSessionsDir holds the in-progress chunk files for resumable uploads,
+// separate from the material storage providers since sessions are transient.
+const UploadSessionsDir = "./upload_sessions"
+
+// uploadSessionTTL is how long an unfinalized session stays alive before
+// it's considered abandoned.
+const uploadSessionTTL = 24 * time.Hour
+
+// ensureUploadSessionTable creates the tables backing resumable chunked
+// uploads, so sessions and their received chunks survive a server restart.
+func ensureUploadSessionTable() error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS upload_session (
+		id TEXT PRIMARY KEY,
+		subject_id TEXT NOT NULL,
+		title TEXT NOT NULL,
+		logical_type TEXT NOT NULL,
+		description TEXT,
+		original_name TEXT NOT NULL,
+		mime_type TEXT,
+		expected_size INTEGER NOT NULL,
+		expected_hash TEXT,
+		created_at TEXT NOT NULL,
+		expires_at TEXT NOT NULL
+	);`); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS upload_chunk (
+		session_id TEXT NOT NULL,
+		chunk_index INTEGER NOT NULL,
+		byte_size INTEGER NOT NULL,
+		PRIMARY KEY (session_id, chunk_index)
+	);`)
+	return err
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func sessionDir(id string) string {
+	return filepath.Join(UploadSessionsDir, id)
+}
+
+func chunkPath(id string, n int) string {
+	return filepath.Join(sessionDir(id), fmt.Sprintf("%d.chunk", n))
+}
+
+// registerUploadRoutes wires the session/chunk/finalize endpoints under api.
+func registerUploadRoutes(api *gin.RouterGroup) {
+	api.POST("/material/upload/session", auth.RequireValidKey(db), func(c *gin.Context) {
+		var req struct {
+			SubjectID    string `json:"subject_id"`
+			Title        string `json:"title"`
+			LogicalType  string `json:"logical_type"`
+			Description  string `json:"description"`
+			OriginalName string `json:"original_name"`
+			MimeType     string `json:"mime_type"`
+			ExpectedSize int64  `json:"expected_size"`
+			ExpectedHash string `json:"expected_hash"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if !auth.AllowsSubject(c, strings.TrimSpace(req.SubjectID)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "key not authorized for this subject"})
+			return
+		}
+
+		id, err := newSessionID()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := os.MkdirAll(sessionDir(id), 0755); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create session directory"})
+			return
+		}
+
+		now := time.Now()
+		_, err = db.Exec(`INSERT INTO upload_session
+			(id, subject_id, title, logical_type, description, original_name, mime_type, expected_size, expected_hash, created_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, strings.TrimSpace(req.SubjectID), strings.TrimSpace(req.Title), strings.TrimSpace(req.LogicalType),
+			strings.TrimSpace(req.Description), req.OriginalName, req.MimeType, req.ExpectedSize, req.ExpectedHash,
+			now.Format(time.RFC3339), now.Add(uploadSessionTTL).Format(time.RFC3339))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"upload_id": id})
+	})
+
+	api.PUT("/material/upload/:id/chunk/:n", auth.RequireValidKey(db), func(c *gin.Context) {
+		id := c.Param("id")
+		n, err := strconv.Atoi(c.Param("n"))
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chunk index"})
+			return
+		}
+
+		sess, ok, err := getUploadSession(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown upload session"})
+			return
+		}
+		if !auth.AllowsSubject(c, sess.SubjectID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "key not authorized for this subject"})
+			return
+		}
+
+		f, err := os.Create(chunkPath(id, n))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write chunk"})
+			return
+		}
+		defer f.Close()
+
+		written, err := io.Copy(f, c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write chunk"})
+			return
+		}
+
+		if _, err := db.Exec(`INSERT INTO upload_chunk (session_id, chunk_index, byte_size) VALUES (?, ?, ?)
+			ON CONFLICT(session_id, chunk_index) DO UPDATE SET byte_size = excluded.byte_size`, id, n, written); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "ok", "chunk": n, "bytes": written})
+	})
+
+	api.POST("/material/upload/:id/finalize", auth.RequireValidKey(db), func(c *gin.Context) {
+		id := c.Param("id")
+
+		sess, ok, err := getUploadSession(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown upload session"})
+			return
+		}
+		if !auth.AllowsSubject(c, sess.SubjectID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "key not authorized for this subject"})
+			return
+		}
+
+		chunkCount, totalSize, err := countChunks(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if sess.ExpectedSize > 0 && totalSize != sess.ExpectedSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "received size does not match expected_size"})
+			return
+		}
+
+		scheme, provider := storage.providerFor(sess.SubjectID)
+		if provider == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "No storage provider configured"})
+			return
+		}
+
+		ts := time.Now().Unix()
+		safeName := strings.ReplaceAll(sess.OriginalName, " ", "_")
+		storedName := fmt.Sprintf("%d_%s", ts, safeName)
+
+		reassembled, err := newChunkReader(id, chunkCount)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer reassembled.Close()
+
+		storedPath, hash, err := saveDeduped(context.Background(), provider, scheme, sess.SubjectID, storedName, reassembled)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to assemble upload"})
+			return
+		}
+
+		if sess.ExpectedHash != "" && hash != sess.ExpectedHash {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "content hash mismatch"})
+			return
+		}
+
+		newID, err := addMaterial(Material{
+			SubjectID:    sess.SubjectID,
+			Title:        sess.Title,
+			LogicalType:  sess.LogicalType,
+			FilePath:     storedPath,
+			OriginalName: sess.OriginalName,
+			MimeType:     sess.MimeType,
+			Description:  sess.Description,
+			CreatedAt:    time.Now().Format(time.RFC3339),
+			ContentHash:  hash,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save record to DB"})
+			return
+		}
+
+		removeUploadSession(id)
+		enqueueProcessing(newID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":      "ok",
+			"id":          newID,
+			"stored_path": storedPath,
+		})
+	})
+}
+
+type uploadSession struct {
+	SubjectID    string
+	Title        string
+	LogicalType  string
+	Description  string
+	OriginalName string
+	MimeType     string
+	ExpectedSize int64
+	ExpectedHash string
+}
+
+func getUploadSession(id string) (uploadSession, bool, error) {
+	var s uploadSession
+	var expiresAt string
+	row := db.QueryRow(`SELECT subject_id, title, logical_type, description, original_name, mime_type, expected_size, expected_hash, expires_at
+		FROM upload_session WHERE id = ?`, id)
+	if err := row.Scan(&s.SubjectID, &s.Title, &s.LogicalType, &s.Description, &s.OriginalName, &s.MimeType, &s.ExpectedSize, &s.ExpectedHash, &expiresAt); err != nil {
+		return uploadSession{}, false, nil
+	}
+	// A session past its expires_at is abandoned and treated as unknown.
+	if t, err := time.Parse(time.RFC3339, expiresAt); err == nil && time.Now().After(t) {
+		return uploadSession{}, false, nil
+	}
+	return s, true, nil
+}
+
+// countChunks returns how many contiguous chunks (0..n-1) have been
+// received and their combined byte size.
+func countChunks(id string) (count int, totalSize int64, err error) {
+	rows, err := db.Query("SELECT chunk_index, byte_size FROM upload_chunk WHERE session_id = ? ORDER BY chunk_index ASC", id)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	expected := 0
+	for rows.Next() {
+		var idx int
+		var size int64
+		if err := rows.Scan(&idx, &size); err != nil {
+			return 0, 0, err
+		}
+		if idx != expected {
+			return expected, totalSize, fmt.Errorf("missing chunk %d", expected)
+		}
+		totalSize += size
+		expected++
+	}
+	return expected, totalSize, nil
+}
+
+func removeUploadSession(id string) {
+	_, _ = db.Exec("DELETE FROM upload_chunk WHERE session_id = ?", id)
+	_, _ = db.Exec("DELETE FROM upload_session WHERE id = ?", id)
+	_ = os.RemoveAll(sessionDir(id))
+}
+
+// chunkReader concatenates the on-disk chunk files for a session, in order,
+// as a single io.Reader.
+type chunkReader struct {
+	id      string
+	n       int
+	current int
+	file    *os.File
+}
+
+func newChunkReader(id string, n int) (*chunkReader, error) {
+	if err := os.MkdirAll(sessionDir(id), 0755); err != nil {
+		return nil, err
+	}
+	return &chunkReader{id: id, n: n}, nil
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.file == nil {
+			if r.current >= r.n {
+				return 0, io.EOF
+			}
+			f, err := os.Open(chunkPath(r.id, r.current))
+			if err != nil {
+				return 0, err
+			}
+			r.file = f
+		}
+
+		n, err := r.file.Read(p)
+		if err == io.EOF {
+			r.file.Close()
+			r.file = nil
+			r.current++
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.file != nil {
+		return r.file.Close()
+	}
+	return nil
+}